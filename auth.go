@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+	"golang.org/x/term"
+)
+
+// tokenResponse is the shape of the JSON body returned by the `/login`
+// endpoint (or a configurable token URL) for every supported grant type.
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// applyToken copies a token response onto a profile, clearing ExpiresAt when
+// the server did not advertise a lifetime.
+func applyToken(profile *Profile, token tokenResponse) {
+	profile.Token = token.AccessToken
+	if token.RefreshToken != "" {
+		profile.RefreshToken = token.RefreshToken
+	}
+	if token.TokenType != "" {
+		profile.TokenType = token.TokenType
+	}
+	profile.Scope = token.Scope
+	if token.ExpiresIn > 0 {
+		profile.ExpiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second).Unix()
+	} else {
+		profile.ExpiresAt = 0
+	}
+}
+
+// profileTokenURL resolves where token requests are sent for a profile,
+// defaulting to the API's own `/login` endpoint.
+func profileTokenURL(profile *Profile) string {
+	if profile.TokenURL != "" {
+		return profile.TokenURL
+	}
+	return fmt.Sprintf("%s/login", profileBaseURL(profile))
+}
+
+// postTokenRequest POSTs a form-encoded grant to tokenURL and decodes the
+// resulting token response.
+func postTokenRequest(client *apiClient, profile *Profile, tokenURL string, form url.Values) (tokenResponse, error) {
+	req, errReq := http.NewRequest("POST", tokenURL, strings.NewReader(form.Encode()))
+	if errReq != nil {
+		return tokenResponse{}, errReq
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	applyProfileHeaders(profile, req)
+
+	body, stats, err := client.do(req)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	if !strings.Contains(stats.StatusLine, "200") {
+		return tokenResponse{}, fmt.Errorf("Unable to obtain token: %s", stats.StatusLine)
+	}
+
+	var token tokenResponse
+	if errDecode := json.Unmarshal(body, &token); errDecode != nil {
+		return tokenResponse{}, errDecode
+	}
+	return token, nil
+}
+
+// refreshProfileToken exchanges a profile's stored refresh token for a new
+// access token, using the client credentials captured at login time.
+func refreshProfileToken(c *cli.Context, profile *Profile) error {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", profile.RefreshToken)
+	if profile.ClientID != "" {
+		form.Set("client_id", profile.ClientID)
+	}
+	if profile.ClientSecret != "" {
+		form.Set("client_secret", profile.ClientSecret)
+	}
+
+	client, errClient := newAPIClient(c.GlobalBool("verbose"), profile)
+	if errClient != nil {
+		return errClient
+	}
+	token, err := postTokenRequest(client, profile, profileTokenURL(profile), form)
+	if err != nil {
+		return err
+	}
+	applyToken(profile, token)
+	return nil
+}
+
+// passwordGrant performs a `grant_type=password` login, reading the
+// username/password from flags, environment variables, or stdin so that
+// credentials never need to appear in shell history.
+func passwordGrant(client *apiClient, profile *Profile, tokenURL string, c *cli.Context) (tokenResponse, error) {
+	username, errUser := resolveCredential(c, "username", "GRAVITY_USERNAME", "Username: ", false)
+	if errUser != nil {
+		return tokenResponse{}, errUser
+	}
+	password, errPass := resolveCredential(c, "password", "GRAVITY_PASSWORD", "Password: ", true)
+	if errPass != nil {
+		return tokenResponse{}, errPass
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "password")
+	form.Set("username", username)
+	form.Set("password", password)
+	if c.String("client-id") != "" {
+		form.Set("client_id", c.String("client-id"))
+	}
+	if c.String("client-secret") != "" {
+		form.Set("client_secret", c.String("client-secret"))
+	}
+
+	return postTokenRequest(client, profile, tokenURL, form)
+}
+
+// clientCredentialsGrant performs a `grant_type=client_credentials` login.
+func clientCredentialsGrant(client *apiClient, profile *Profile, tokenURL string, c *cli.Context) (tokenResponse, error) {
+	if c.String("client-id") == "" || c.String("client-secret") == "" {
+		return tokenResponse{}, errors.New("Parameters --client-id and --client-secret must be specified for grant type 'client_credentials'")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", c.String("client-id"))
+	form.Set("client_secret", c.String("client-secret"))
+
+	return postTokenRequest(client, profile, tokenURL, form)
+}
+
+// authorizationCodeGrant drives a `grant_type=authorization_code` login with
+// PKCE, opening a local loopback listener to catch the redirect.
+func authorizationCodeGrant(client *apiClient, profile *Profile, tokenURL string, c *cli.Context) (tokenResponse, error) {
+	if c.String("client-id") == "" {
+		return tokenResponse{}, errors.New("Parameter --client-id must be specified for grant type 'authorization_code'")
+	}
+	if c.String("authorize-url") == "" {
+		return tokenResponse{}, errors.New("Parameter --authorize-url must be specified for grant type 'authorization_code'")
+	}
+
+	verifier, challenge, errPKCE := generatePKCE()
+	if errPKCE != nil {
+		return tokenResponse{}, errPKCE
+	}
+
+	code, redirectURI, errCode := captureAuthorizationCode(c, challenge)
+	if errCode != nil {
+		return tokenResponse{}, errCode
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", c.String("client-id"))
+	form.Set("code_verifier", verifier)
+	if c.String("client-secret") != "" {
+		form.Set("client_secret", c.String("client-secret"))
+	}
+
+	return postTokenRequest(client, profile, tokenURL, form)
+}
+
+// captureAuthorizationCode starts a loopback HTTP listener, prints the
+// authorization URL for the user to open, and blocks until the redirect
+// delivers an authorization code.
+func captureAuthorizationCode(c *cli.Context, challenge string) (string, string, error) {
+	listener, errListen := net.Listen("tcp", "127.0.0.1:0")
+	if errListen != nil {
+		return "", "", errListen
+	}
+	defer listener.Close()
+
+	redirectURI := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	codeCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			errCh <- errors.New("Authorization callback did not include a code")
+			fmt.Fprintln(w, "Login failed: no authorization code received.")
+			return
+		}
+		codeCh <- code
+		fmt.Fprintln(w, "Login complete. You may close this window.")
+	})
+	server := &http.Server{Handler: mux}
+	go server.Serve(listener)
+	defer server.Close()
+
+	authorizeURL := fmt.Sprintf(
+		"%s?response_type=code&client_id=%s&redirect_uri=%s&code_challenge=%s&code_challenge_method=S256",
+		c.String("authorize-url"),
+		url.QueryEscape(c.String("client-id")),
+		url.QueryEscape(redirectURI),
+		challenge,
+	)
+	fmt.Printf("Open the following URL to authenticate:\n%s\n", authorizeURL)
+
+	select {
+	case code := <-codeCh:
+		return code, redirectURI, nil
+	case err := <-errCh:
+		return "", "", err
+	}
+}
+
+// generatePKCE creates an S256 PKCE code verifier/challenge pair.
+func generatePKCE() (verifier string, challenge string, err error) {
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(raw)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
+}
+
+// resolveCredential reads a credential from a CLI flag, then an environment
+// variable, then (as a last resort) stdin, so that secrets don't need to be
+// passed on the command line where they'd be saved in shell history.
+func resolveCredential(c *cli.Context, flagName string, envVar string, prompt string, isSecret bool) (string, error) {
+	if v := c.String(flagName); v != "" {
+		return v, nil
+	}
+	if v := os.Getenv(envVar); v != "" {
+		return v, nil
+	}
+
+	fmt.Print(prompt)
+	if isSecret {
+		raw, errRead := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if errRead != nil {
+			return "", errRead
+		}
+		return string(raw), nil
+	}
+
+	line, errRead := bufio.NewReader(os.Stdin).ReadString('\n')
+	if errRead != nil {
+		return "", errRead
+	}
+	return strings.TrimSpace(line), nil
+}
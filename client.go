@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+// requestTimeout is the overall deadline applied to every outgoing request.
+const requestTimeout = 30 * time.Second
+
+// httpStats carries the timing breakdown of a single request, captured via
+// httptrace.ClientTrace, replacing the output previously produced by httpstat.
+type httpStats struct {
+	DNSLookup        time.Duration
+	TCPConnection    time.Duration
+	TLSHandshake     time.Duration
+	ServerProcessing time.Duration
+	ContentTransfer  time.Duration
+	Total            time.Duration
+	StatusLine       string
+}
+
+func (s httpStats) String() string {
+	builder := strings.Builder{}
+	builder.WriteString(fmt.Sprintf("%s\n", s.StatusLine))
+	builder.WriteString(fmt.Sprintf("  DNS Lookup:    %v\n", s.DNSLookup))
+	builder.WriteString(fmt.Sprintf("  TCP Connection: %v\n", s.TCPConnection))
+	builder.WriteString(fmt.Sprintf("  TLS Handshake: %v\n", s.TLSHandshake))
+	builder.WriteString(fmt.Sprintf("  Server Processing: %v\n", s.ServerProcessing))
+	builder.WriteString(fmt.Sprintf("  Content Transfer: %v\n", s.ContentTransfer))
+	builder.WriteString(fmt.Sprintf("  Total: %v", s.Total))
+	return builder.String()
+}
+
+// apiClient issues HTTP requests against the configured Gravity API and
+// records timing statistics for each call. It replaces the previous
+// httpstat/jq/cat shell-outs with an in-process net/http based engine.
+type apiClient struct {
+	httpClient *http.Client
+	isVerbose  bool
+}
+
+// newAPIClient builds a client configured for profile's transport: a Unix
+// domain socket when `socket` is set, and/or mTLS when cert/key/CA files are
+// configured. A nil profile yields a client with default TCP and TLS
+// settings.
+func newAPIClient(isVerbose bool, profile *Profile) (*apiClient, error) {
+	tlsConfig := &tls.Config{}
+	transport := &http.Transport{TLSClientConfig: tlsConfig}
+
+	if profile != nil {
+		if profile.InsecureSkipVerify {
+			tlsConfig.InsecureSkipVerify = true
+		}
+		if profile.CertFile != "" && profile.KeyFile != "" {
+			cert, errCert := tls.LoadX509KeyPair(profile.CertFile, profile.KeyFile)
+			if errCert != nil {
+				return nil, errCert
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		if profile.CAFile != "" {
+			caBytes, errRead := ioutil.ReadFile(profile.CAFile)
+			if errRead != nil {
+				return nil, errRead
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(caBytes) {
+				return nil, fmt.Errorf("unable to parse CA certificate %s", profile.CAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if profile.Socket != "" {
+			socketPath := profile.Socket
+			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			}
+		}
+	}
+
+	return &apiClient{
+		httpClient: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: transport,
+		},
+		isVerbose: isVerbose,
+	}, nil
+}
+
+// do sends req, buffering the response body in memory, and returns the body
+// alongside timing statistics gathered through httptrace.
+func (a *apiClient) do(req *http.Request) ([]byte, httpStats, error) {
+	var stats httpStats
+	var dnsStart, connectStart, tlsStart, requestWritten, start time.Time
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			if !dnsStart.IsZero() {
+				stats.DNSLookup = time.Since(dnsStart)
+			}
+		},
+		ConnectStart: func(string, string) { connectStart = time.Now() },
+		ConnectDone: func(string, string, error) {
+			if !connectStart.IsZero() {
+				stats.TCPConnection = time.Since(connectStart)
+			}
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			if !tlsStart.IsZero() {
+				stats.TLSHandshake = time.Since(tlsStart)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			requestWritten = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			if !requestWritten.IsZero() {
+				stats.ServerProcessing = time.Since(requestWritten)
+			}
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	if a.isVerbose {
+		fmt.Println("Request executed:", req.Method, req.URL.String())
+	}
+
+	start = time.Now()
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, stats, err
+	}
+	defer resp.Body.Close()
+
+	transferStart := time.Now()
+	body, errRead := ioutil.ReadAll(resp.Body)
+	if errRead != nil {
+		return nil, stats, errRead
+	}
+	stats.ContentTransfer = time.Since(transferStart)
+	stats.Total = time.Since(start)
+	stats.StatusLine = resp.Status
+
+	return body, stats, nil
+}
+
+// runSelector applies a jq-style filter to a JSON document using a pure-Go
+// JSONPath/jq engine, replacing the external `jq` binary.
+func runSelector(selector string, data []byte) (string, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return "", nil
+	}
+
+	query, errParse := gojq.Parse(selector)
+	if errParse != nil {
+		return "", errParse
+	}
+
+	var input interface{}
+	if errUnmarshal := json.Unmarshal(data, &input); errUnmarshal != nil {
+		return "", errUnmarshal
+	}
+
+	builder := strings.Builder{}
+	iter := query.Run(input)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if errVal, isErr := v.(error); isErr {
+			return "", errVal
+		}
+		out, errMarshal := json.Marshal(v)
+		if errMarshal != nil {
+			return "", errMarshal
+		}
+		builder.Write(out)
+		builder.WriteString("\n")
+	}
+
+	return strings.TrimRight(builder.String(), "\n"), nil
+}
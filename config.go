@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+const configuationFilename = ".gravity-api.yaml"
+
+// defaultProfileName is used by the legacy top-level `configure` command
+// when no profile has been selected yet.
+const defaultProfileName = "default"
+
+// profileEnvVar lets the active profile be selected without a flag, e.g. in
+// scripts or CI.
+const profileEnvVar = "GRAVITY_PROFILE"
+
+// defaultTokenRefreshSkew is how far ahead of expiry a stored access token
+// is proactively refreshed, unless overridden by --token-refresh-skew.
+const defaultTokenRefreshSkew = 60
+
+// Profile holds the connection details for a single named environment.
+type Profile struct {
+	URL          string            `yaml:"url"`
+	Token        string            `yaml:"token"`
+	RefreshToken string            `yaml:"refresh_token,omitempty"`
+	TokenType    string            `yaml:"token_type,omitempty"`
+	Scope        string            `yaml:"scope,omitempty"`
+	ExpiresAt    int64             `yaml:"expires_at,omitempty"`
+	ClientID     string            `yaml:"client_id,omitempty"`
+	ClientSecret string            `yaml:"client_secret,omitempty"`
+	TokenURL     string            `yaml:"token_url,omitempty"`
+	KeysPath     string            `yaml:"keys_path,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty"`
+
+	// Socket, when set, routes requests over a Unix domain socket instead of
+	// TCP; URL still supplies the Host header and path resolution.
+	Socket string `yaml:"socket,omitempty"`
+
+	// mTLS settings, used whether talking to Socket or a remote HTTPS URL.
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	CAFile             string `yaml:"ca_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// Configuration stores every known profile plus which one is active.
+type Configuration struct {
+	Current  string              `yaml:"current"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+
+	// LegacyURL/LegacyToken capture the pre-profile, single-environment
+	// schema (top-level `url`/`token`) so loadConfiguration can migrate an
+	// existing user's configuration into a "default" profile instead of
+	// silently discarding it. Cleared once migrated.
+	LegacyURL   string `yaml:"url,omitempty"`
+	LegacyToken string `yaml:"token,omitempty"`
+}
+
+func getConfigPath(configuationFilename string) (string, error) {
+	homeDir, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, configuationFilename), nil
+}
+
+func getStoredConfiguration(configPath string, config *Configuration) error {
+	bytes, errRead := ioutil.ReadFile(configPath)
+	if errRead != nil {
+		return errRead
+	}
+	if errDeserialise := yaml.Unmarshal(bytes, config); errDeserialise != nil {
+		return errDeserialise
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]*Profile{}
+	}
+	return nil
+}
+
+func writeConfiguration(configPath string, config *Configuration) error {
+	bytes, errSerialise := yaml.Marshal(config)
+	if errSerialise != nil {
+		return errSerialise
+	}
+	f, errOpen := os.Create(configPath)
+	if errOpen != nil {
+		return errOpen
+	}
+	defer f.Close()
+	_, errWrite := f.Write(bytes)
+	return errWrite
+}
+
+// loadConfiguration reads the configuration file, tolerating a missing file
+// so that profile commands can create it on first use.
+func loadConfiguration(configPath string) (*Configuration, error) {
+	config := &Configuration{Profiles: map[string]*Profile{}}
+	if _, errExist := os.Stat(configPath); errExist != nil {
+		return config, nil
+	}
+	if err := getStoredConfiguration(configPath, config); err != nil {
+		return nil, err
+	}
+	if migrateLegacyConfiguration(config) {
+		if err := writeConfiguration(configPath, config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// migrateLegacyConfiguration folds a pre-profile configuration file's
+// top-level `url`/`token` keys into a "default" profile, so upgrading to
+// named profiles does not silently discard an existing user's settings and
+// stored token. Reports whether it made any change.
+func migrateLegacyConfiguration(config *Configuration) bool {
+	if config.LegacyURL == "" && config.LegacyToken == "" {
+		return false
+	}
+
+	if _, exists := config.Profiles[defaultProfileName]; !exists {
+		config.Profiles[defaultProfileName] = &Profile{
+			URL:   config.LegacyURL,
+			Token: config.LegacyToken,
+		}
+	}
+	if config.Current == "" {
+		config.Current = defaultProfileName
+	}
+	config.LegacyURL = ""
+	config.LegacyToken = ""
+	return true
+}
+
+// activeProfileName resolves which profile a command should operate on:
+// --profile/-P takes precedence, then $GRAVITY_PROFILE, then the
+// configuration's current pointer.
+func activeProfileName(c *cli.Context, config *Configuration) string {
+	if name := c.GlobalString("profile"); name != "" {
+		return name
+	}
+	if name := os.Getenv(profileEnvVar); name != "" {
+		return name
+	}
+	return config.Current
+}
+
+// getValidatedConfiguration resolves the active profile and ensures it is
+// usable for making API calls.
+func getValidatedConfiguration(c *cli.Context) (*Profile, error) {
+	configPath, errHome := getConfigPath(configuationFilename)
+	if errHome != nil {
+		return nil, errHome
+	}
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return nil, errLoad
+	}
+
+	name := activeProfileName(c, config)
+	if name == "" {
+		return nil, errors.New("Please run command 'configure add' and try again")
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("Profile '%s' does not exist. Run 'configure list' to see available profiles", name)
+	}
+
+	if profile.URL == "" && profile.Socket == "" {
+		return nil, errors.New("Please run command 'configure' and try again")
+	}
+	if profile.Token == "" {
+		return nil, errors.New("Please run command 'login' and try again")
+	}
+
+	if profile.needsRefresh(tokenRefreshSkew(c)) {
+		if err := refreshProfileToken(c, profile); err != nil {
+			return nil, err
+		}
+		if err := writeConfiguration(configPath, config); err != nil {
+			return nil, err
+		}
+	}
+
+	return profile, nil
+}
+
+// unixSocketPlaceholderURL is the base URL used to build requests for a
+// socket-only profile: the path is what matters, and the host is otherwise
+// unused since DialContext is overridden to dial the socket directly. This
+// mirrors how the Docker CLI addresses its Unix domain socket.
+const unixSocketPlaceholderURL = "http://unix"
+
+// profileBaseURL resolves the base URL requests are built against: the
+// configured URL, or a placeholder host when the profile only declares a
+// Unix domain socket.
+func profileBaseURL(profile *Profile) string {
+	if profile.URL != "" {
+		return profile.URL
+	}
+	return unixSocketPlaceholderURL
+}
+
+// applyProfileHeaders sets a profile's configured default headers onto an
+// outgoing request, without overriding headers the caller already set.
+func applyProfileHeaders(profile *Profile, req *http.Request) {
+	for key, value := range profile.Headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	}
+}
+
+// needsRefresh reports whether the profile's access token will expire within
+// skew and a refresh token is available to renew it.
+func (p *Profile) needsRefresh(skew time.Duration) bool {
+	if p.RefreshToken == "" || p.ExpiresAt == 0 {
+		return false
+	}
+	return time.Now().Add(skew).Unix() >= p.ExpiresAt
+}
+
+// tokenRefreshSkew resolves the configurable expiry skew from
+// --token-refresh-skew, falling back to defaultTokenRefreshSkew.
+func tokenRefreshSkew(c *cli.Context) time.Duration {
+	skew := c.GlobalInt("token-refresh-skew")
+	if skew <= 0 {
+		skew = defaultTokenRefreshSkew
+	}
+	return time.Duration(skew) * time.Second
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/urfave/cli"
+)
+
+// defaultKeysPath is the resource under the API base URL that the keys
+// subcommands operate on, overridable per profile via `keys_path`.
+const defaultKeysPath = "/keys"
+
+func keysPath(profile *Profile) string {
+	if profile.KeysPath != "" {
+		return profile.KeysPath
+	}
+	return defaultKeysPath
+}
+
+func keysListCommand(c *cli.Context) error {
+	profile, errConfig := getValidatedConfiguration(c)
+	if errConfig != nil {
+		return errConfig
+	}
+
+	body, err := callKeysAPI(c, profile, "GET", keysPath(profile), nil)
+	if err != nil {
+		return err
+	}
+	return printRendered(c, body)
+}
+
+func keysAddCommand(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Parameter <name> must be specified")
+	}
+
+	profile, errConfig := getValidatedConfiguration(c)
+	if errConfig != nil {
+		return errConfig
+	}
+
+	key := c.String("key")
+	isGenerated := key == ""
+	if isGenerated {
+		generatedKey, errGenerate := generateAPIKey()
+		if errGenerate != nil {
+			return errGenerate
+		}
+		key = generatedKey
+	}
+
+	payload, errMarshal := json.Marshal(map[string]string{
+		"name": name,
+		"key":  hashAPIKey(key),
+	})
+	if errMarshal != nil {
+		return errMarshal
+	}
+
+	body, errCall := callKeysAPI(c, profile, "POST", keysPath(profile), payload)
+	if errCall != nil {
+		return errCall
+	}
+
+	if isGenerated {
+		fmt.Printf("Generated key for '%s': %s\n", name, key)
+		fmt.Println("Store this value now, it will not be shown again.")
+	}
+	return printRendered(c, body)
+}
+
+func keysDeleteCommand(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Parameter <name> must be specified")
+	}
+
+	profile, errConfig := getValidatedConfiguration(c)
+	if errConfig != nil {
+		return errConfig
+	}
+
+	body, errCall := callKeysAPI(c, profile, "DELETE", fmt.Sprintf("%s/%s", keysPath(profile), name), nil)
+	if errCall != nil {
+		return errCall
+	}
+	return printRendered(c, body)
+}
+
+func keysPruneCommand(c *cli.Context) error {
+	duration := c.String("duration")
+	if duration == "" {
+		return errors.New("Parameter --duration must be specified")
+	}
+	if _, errParse := time.ParseDuration(duration); errParse != nil {
+		return fmt.Errorf("Parameter --duration is not a valid duration: %v", errParse)
+	}
+
+	profile, errConfig := getValidatedConfiguration(c)
+	if errConfig != nil {
+		return errConfig
+	}
+
+	body, errCall := callKeysAPI(c, profile, "DELETE", fmt.Sprintf("%s?olderThan=%s", keysPath(profile), duration), nil)
+	if errCall != nil {
+		return errCall
+	}
+	return printRendered(c, body)
+}
+
+// callKeysAPI issues an authenticated request against the configured keys
+// endpoint and returns the raw response body.
+func callKeysAPI(c *cli.Context, profile *Profile, verb string, resource string, payload []byte) ([]byte, error) {
+	var bodyReader io.Reader
+	if payload != nil {
+		bodyReader = bytes.NewReader(payload)
+	}
+	req, errReq := http.NewRequest(verb, fmt.Sprintf("%s%s", profileBaseURL(profile), resource), bodyReader)
+	if errReq != nil {
+		return nil, errReq
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if profile.Token != "" {
+		req.Header.Set("authorization", fmt.Sprintf("Bearer %s", profile.Token))
+	}
+	applyProfileHeaders(profile, req)
+
+	client, errClient := newAPIClient(c.GlobalBool("verbose"), profile)
+	if errClient != nil {
+		return nil, errClient
+	}
+	body, _, err := client.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// generateAPIKey produces a cryptographically random API key, sent to the
+// server as its SHA-512 hash so the plaintext value is only ever shown once.
+func generateAPIKey() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func hashAPIKey(key string) string {
+	sum := sha512.Sum512([]byte(key))
+	return fmt.Sprintf("%x", sum)
+}
+
+func printRendered(c *cli.Context, body []byte) error {
+	output, errRender := renderResponse(c, body)
+	if errRender != nil {
+		return errRender
+	}
+	fmt.Println(output)
+	return nil
+}
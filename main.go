@@ -1,57 +1,26 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
 	"strings"
 
-	"gopkg.in/yaml.v2"
-
-	homedir "github.com/mitchellh/go-homedir"
 	"github.com/urfave/cli"
 )
 
-// Configuration stores configuration information
-type Configuration struct {
-	URL   string `yaml:"url"`
-	Token string `yaml:"token"`
-}
-
-// Command indicates the requirements of executing a command
-type Command interface {
-	GetCommand(name string, isVerbose bool, args []string) *exec.Cmd
-}
-
-// CommandLine executes commands
-type CommandLine struct {
-}
-
-// GetCommand retrieve the command to be executed
-func (c *CommandLine) GetCommand(name string, isVerbose bool, args []string) *exec.Cmd {
-	if isVerbose {
-		fmt.Println("Command executed:", name, args)
-	}
-	return exec.Command(name, args...)
-}
-
-const configuationFilename = ".gravity-api.yaml"
-const responseTempFilename = "gravity-api-response"
-
 func main() {
 	resourceFlag := cli.StringFlag{
 		Name:  "resource, r",
 		Usage: "URI to the resource API",
 	}
 	selectorFlag := cli.StringFlag{
-		Name:  "selector, s",
-		Usage: "jq selector to the json response",
+		Name:  "selector, s, jsonpath",
+		Usage: "jq/JSONPath selector to the json response",
 		Value: ".",
 	}
 	paramFileFlag := cli.StringFlag{
@@ -101,6 +70,67 @@ func main() {
 					Usage:  "Show the current configuration",
 					Action: showConfigurationCommand,
 				},
+				cli.Command{
+					Name:      "add",
+					Usage:     "Add or update a named profile",
+					ArgsUsage: "<profile>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "url, u",
+							Usage: "URL to the API",
+						},
+						cli.StringFlag{
+							Name:  "token, t",
+							Usage: "Optional, token to make API calls",
+						},
+						cli.StringFlag{
+							Name:  "socket",
+							Usage: "Unix domain socket to dial instead of TCP, e.g. /var/run/gravity.sock",
+						},
+						cli.StringFlag{
+							Name:  "cert-file",
+							Usage: "Client certificate for mTLS",
+						},
+						cli.StringFlag{
+							Name:  "key-file",
+							Usage: "Client private key for mTLS",
+						},
+						cli.StringFlag{
+							Name:  "ca-file",
+							Usage: "CA certificate used to verify the server",
+						},
+						cli.BoolFlag{
+							Name:  "insecure-skip-verify",
+							Usage: "Disable TLS certificate verification",
+						},
+						cli.StringFlag{
+							Name:  "keys-path",
+							Usage: "Override the resource path used by the keys subcommands",
+						},
+						cli.StringSliceFlag{
+							Name:  "header",
+							Usage: "Default header sent with every request, as key=value (repeatable)",
+						},
+					},
+					Action: configureAddCommand,
+				},
+				cli.Command{
+					Name:      "remove",
+					Usage:     "Remove a named profile",
+					ArgsUsage: "<profile>",
+					Action:    configureRemoveCommand,
+				},
+				cli.Command{
+					Name:      "use",
+					Usage:     "Select the profile used by default",
+					ArgsUsage: "<profile>",
+					Action:    configureUseCommand,
+				},
+				cli.Command{
+					Name:   "list",
+					Usage:  "List the known profiles",
+					Action: configureListCommand,
+				},
 			},
 		},
 		{
@@ -115,6 +145,27 @@ func main() {
 					Name:  "password, p",
 					Usage: "Password of login of API",
 				},
+				cli.StringFlag{
+					Name:  "grant-type, g",
+					Usage: "OAuth2 grant type: password, client_credentials or authorization_code",
+					Value: "password",
+				},
+				cli.StringFlag{
+					Name:  "client-id",
+					Usage: "OAuth2 client ID",
+				},
+				cli.StringFlag{
+					Name:  "client-secret",
+					Usage: "OAuth2 client secret",
+				},
+				cli.StringFlag{
+					Name:  "token-url",
+					Usage: "Override the token endpoint (defaults to the profile's saved token URL, else <url>/login)",
+				},
+				cli.StringFlag{
+					Name:  "authorize-url",
+					Usage: "Authorization endpoint, required for grant type authorization_code",
+				},
 			},
 			Action: loginCommand,
 		},
@@ -148,12 +199,70 @@ func main() {
 			Flags:  queryFlags,
 			Action: deleteCommand,
 		},
+		{
+			Name:  "keys",
+			Usage: "Manage server-side API keys",
+			Subcommands: []cli.Command{
+				cli.Command{
+					Name:   "list",
+					Usage:  "List API keys",
+					Action: keysListCommand,
+				},
+				cli.Command{
+					Name:      "add",
+					Usage:     "Add an API key, generating one when --key is not given",
+					ArgsUsage: "<name>",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "key",
+							Usage: "Key value, auto-generated when omitted",
+						},
+					},
+					Action: keysAddCommand,
+				},
+				cli.Command{
+					Name:      "delete",
+					Usage:     "Delete an API key",
+					ArgsUsage: "<name>",
+					Action:    keysDeleteCommand,
+				},
+				cli.Command{
+					Name:  "prune",
+					Usage: "Delete API keys older than --duration",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:  "duration",
+							Usage: "Keys older than this duration are deleted, e.g. 24h",
+						},
+					},
+					Action: keysPruneCommand,
+				},
+			},
+		},
 	}
 	app.Flags = []cli.Flag{
 		cli.BoolFlag{
 			Name:  "verbose",
 			Usage: "Verbose mode",
 		},
+		cli.StringFlag{
+			Name:  "profile, P",
+			Usage: "Name of the configuration profile to use",
+		},
+		cli.IntFlag{
+			Name:  "token-refresh-skew",
+			Usage: "Seconds before expiry at which the access token is refreshed",
+			Value: defaultTokenRefreshSkew,
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Usage: "Output format: raw, json, yaml, table or csv",
+			Value: outputRaw,
+		},
+		cli.StringFlag{
+			Name:  "template",
+			Usage: "text/template applied to the response, overriding --output",
+		},
 	}
 	err := app.Run(os.Args)
 	if err != nil {
@@ -166,24 +275,29 @@ func configureCommand(c *cli.Context) error {
 		return errors.New("No parameters are specified. See --help for options")
 	}
 
-	config := Configuration{}
-
-	isCreate := true
 	configPath, errHome := getConfigPath(configuationFilename)
 	if errHome != nil {
 		return errHome
 	}
-	if _, errExist := os.Stat(configPath); errExist == nil {
-		if err := getStoredConfiguration(configPath, &config); err != nil {
-			return err
-		}
-		isCreate = false
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return errLoad
 	}
-	bytes, errSerialise := getConfigurationBytes(c.String("url"), c.String("token"), &config)
-	if errSerialise != nil {
-		return errSerialise
+
+	name := activeProfileName(c, config)
+	if name == "" {
+		name = defaultProfileName
+	}
+	isCreate := config.Profiles[name] == nil
+	if errUpsert := upsertProfile(config, name, c.String("url"), c.String("token")); errUpsert != nil {
+		return errUpsert
+	}
+	if config.Current == "" {
+		config.Current = name
+	}
+	if err := writeConfiguration(configPath, config); err != nil {
+		return err
 	}
-	writeConfiguration(configPath, bytes)
 
 	if isCreate {
 		fmt.Printf("Configuration file %s has been created.\n", configPath)
@@ -198,57 +312,288 @@ func showConfigurationCommand(c *cli.Context) error {
 	if errHome != nil {
 		return errHome
 	}
-	config := Configuration{}
-	if err := getStoredConfiguration(configPath, &config); err != nil {
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return errLoad
+	}
+
+	name := activeProfileName(c, config)
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return fmt.Errorf("Profile '%s' does not exist. Run 'configure list' to see available profiles", name)
+	}
+
+	format := c.GlobalString("output")
+	if (format != "" && format != outputRaw) || c.GlobalString("template") != "" {
+		raw, errMarshal := json.Marshal(struct {
+			Profile string `json:"profile"`
+			URL     string `json:"url"`
+			Token   string `json:"token"`
+		}{Profile: name, URL: profile.URL, Token: profile.Token})
+		if errMarshal != nil {
+			return errMarshal
+		}
+		rendered, errRender := renderResponse(c, raw)
+		if errRender != nil {
+			return errRender
+		}
+		fmt.Println(rendered)
+		return nil
+	}
+
+	fmt.Printf("profile: %s\n", name)
+	fmt.Printf("url: %s\n", profile.URL)
+	fmt.Printf("token: %s\n", profile.Token)
+	return nil
+}
+
+func configureAddCommand(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Parameter <profile> must be specified")
+	}
+
+	configPath, errHome := getConfigPath(configuationFilename)
+	if errHome != nil {
+		return errHome
+	}
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return errLoad
+	}
+
+	if err := upsertProfile(config, name, c.String("url"), c.String("token")); err != nil {
 		return err
 	}
-	fmt.Printf("url: %s\n", config.URL)
-	fmt.Printf("token: %s\n", config.Token)
+	profile := config.Profiles[name]
+	if c.String("socket") != "" {
+		profile.Socket = c.String("socket")
+	}
+	if c.String("cert-file") != "" {
+		profile.CertFile = c.String("cert-file")
+	}
+	if c.String("key-file") != "" {
+		profile.KeyFile = c.String("key-file")
+	}
+	if c.String("ca-file") != "" {
+		profile.CAFile = c.String("ca-file")
+	}
+	if c.Bool("insecure-skip-verify") {
+		profile.InsecureSkipVerify = true
+	}
+	if c.String("keys-path") != "" {
+		profile.KeysPath = c.String("keys-path")
+	}
+	if headers := c.StringSlice("header"); len(headers) > 0 {
+		parsed, errParse := parseHeaders(headers)
+		if errParse != nil {
+			return errParse
+		}
+		profile.Headers = parsed
+	}
+	if config.Current == "" {
+		config.Current = name
+	}
+	if err := writeConfiguration(configPath, config); err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile '%s' has been saved.\n", name)
 	return nil
 }
 
-func loginCommand(c *cli.Context) error {
-	if c.String("username") == "" {
-		return errors.New("Parameter --username must be specified")
+func configureRemoveCommand(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Parameter <profile> must be specified")
+	}
+
+	configPath, errHome := getConfigPath(configuationFilename)
+	if errHome != nil {
+		return errHome
+	}
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return errLoad
+	}
+
+	if _, ok := config.Profiles[name]; !ok {
+		return fmt.Errorf("Profile '%s' does not exist", name)
+	}
+	delete(config.Profiles, name)
+	if config.Current == name {
+		config.Current = ""
+	}
+	if err := writeConfiguration(configPath, config); err != nil {
+		return err
 	}
-	if c.String("password") == "" {
-		return errors.New("Parameter --password must be specified")
+
+	fmt.Printf("Profile '%s' has been removed.\n", name)
+	return nil
+}
+
+func configureUseCommand(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("Parameter <profile> must be specified")
 	}
+
 	configPath, errHome := getConfigPath(configuationFilename)
 	if errHome != nil {
 		return errHome
 	}
-	config := Configuration{}
-	if err := getStoredConfiguration(configPath, &config); err != nil {
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return errLoad
+	}
+
+	if _, ok := config.Profiles[name]; !ok {
+		return fmt.Errorf("Profile '%s' does not exist. Run 'configure list' to see available profiles", name)
+	}
+	config.Current = name
+	if err := writeConfiguration(configPath, config); err != nil {
 		return err
 	}
-	cmd := CommandLine{}
-	output, err := executeHTTPCommand(&cmd, c.GlobalBool("verbose"), "", []string{
-		"-X",
-		"POST",
-		"-d",
-		fmt.Sprintf("grant_type=password&username=%s&password=%s", c.String("username"), c.String("password")),
-		fmt.Sprintf("%s/login", config.URL),
-	})
+
+	fmt.Printf("Now using profile '%s'.\n", name)
+	return nil
+}
+
+func configureListCommand(c *cli.Context) error {
+	configPath, errHome := getConfigPath(configuationFilename)
+	if errHome != nil {
+		return errHome
+	}
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return errLoad
+	}
+
+	if len(config.Profiles) == 0 {
+		fmt.Println("No profiles configured. Run 'configure add <profile>' to create one.")
+		return nil
+	}
+
+	format := c.GlobalString("output")
+	if (format != "" && format != outputRaw) || c.GlobalString("template") != "" {
+		type profileRow struct {
+			Name    string `json:"name"`
+			URL     string `json:"url"`
+			Current bool   `json:"current"`
+		}
+		rows := []profileRow{}
+		for name, profile := range config.Profiles {
+			rows = append(rows, profileRow{Name: name, URL: profile.URL, Current: name == config.Current})
+		}
+		raw, errMarshal := json.Marshal(rows)
+		if errMarshal != nil {
+			return errMarshal
+		}
+		rendered, errRender := renderResponse(c, raw)
+		if errRender != nil {
+			return errRender
+		}
+		fmt.Println(rendered)
+		return nil
+	}
+
+	for name := range config.Profiles {
+		if name == config.Current {
+			fmt.Printf("* %s\n", name)
+		} else {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	return nil
+}
+
+func upsertProfile(config *Configuration, name string, url string, token string) error {
+	if config.Profiles == nil {
+		config.Profiles = map[string]*Profile{}
+	}
+	profile, ok := config.Profiles[name]
+	if !ok {
+		profile = &Profile{}
+		config.Profiles[name] = profile
+	}
+	if url != "" {
+		profile.URL = url
+	}
+	if token != "" {
+		profile.Token = token
+	}
+	return nil
+}
+
+// parseHeaders converts repeated --header key=value flag values into a map,
+// as stored on Profile.Headers.
+func parseHeaders(raw []string) (map[string]string, error) {
+	headers := map[string]string{}
+	for _, entry := range raw {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Parameter --header must be in the form key=value, got '%s'", entry)
+		}
+		headers[parts[0]] = parts[1]
+	}
+	return headers, nil
+}
+
+func loginCommand(c *cli.Context) error {
+	configPath, errHome := getConfigPath(configuationFilename)
+	if errHome != nil {
+		return errHome
+	}
+	config, errLoad := loadConfiguration(configPath)
+	if errLoad != nil {
+		return errLoad
+	}
+	name := activeProfileName(c, config)
+	profile, ok := config.Profiles[name]
+	if !ok {
+		return fmt.Errorf("Profile '%s' does not exist. Run 'configure add' and try again", name)
+	}
+
+	tokenURL := c.String("token-url")
+	if tokenURL == "" {
+		tokenURL = profileTokenURL(profile)
+	}
+	client, errClient := newAPIClient(c.GlobalBool("verbose"), profile)
+	if errClient != nil {
+		return errClient
+	}
+
+	var (
+		token tokenResponse
+		err   error
+	)
+	switch c.String("grant-type") {
+	case "", "password":
+		token, err = passwordGrant(client, profile, tokenURL, c)
+	case "client_credentials":
+		token, err = clientCredentialsGrant(client, profile, tokenURL, c)
+	case "authorization_code":
+		token, err = authorizationCodeGrant(client, profile, tokenURL, c)
+	default:
+		err = fmt.Errorf("Unsupported grant type '%s'", c.String("grant-type"))
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Println(output)
-	if !strings.Contains(output, "200 OK") {
-		return errors.New("Unable to login")
+
+	applyToken(profile, token)
+	if c.String("client-id") != "" {
+		profile.ClientID = c.String("client-id")
+	}
+	if c.String("client-secret") != "" {
+		profile.ClientSecret = c.String("client-secret")
 	}
-	jqOutput, errJq := executeJqCommand(&cmd, c.GlobalBool("verbose"), []string{
-		".access_token",
-	})
-	if errJq != nil {
-		return errJq
+	if c.String("token-url") != "" {
+		profile.TokenURL = c.String("token-url")
 	}
-	token := strings.Replace(strings.Replace(jqOutput, "\"", "", -1), "\n", "", -1)
-	bytes, errSerialise := getConfigurationBytes(config.URL, token, &config)
-	if errSerialise != nil {
-		return errSerialise
+	if err := writeConfiguration(configPath, config); err != nil {
+		return err
 	}
-	writeConfiguration(configPath, bytes)
 
 	fmt.Printf("Configuration file %s has been updated.\n", configPath)
 
@@ -256,31 +601,26 @@ func loginCommand(c *cli.Context) error {
 }
 
 func getCommand(c *cli.Context) error {
-	cmd := CommandLine{}
-	return executeQueryStringCommands(c, &cmd, "GET")
+	return executeQueryStringCommands(c, "GET")
 }
 
 func postCommand(c *cli.Context) error {
-	cmd := CommandLine{}
-	return executeDataCommands(c, &cmd, "POST")
+	return executeDataCommands(c, "POST")
 }
 
 func putCommand(c *cli.Context) error {
-	cmd := CommandLine{}
-	return executeDataCommands(c, &cmd, "PUT")
+	return executeDataCommands(c, "PUT")
 }
 
 func patchCommand(c *cli.Context) error {
-	cmd := CommandLine{}
-	return executeDataCommands(c, &cmd, "PATCH")
+	return executeDataCommands(c, "PATCH")
 }
 
 func deleteCommand(c *cli.Context) error {
-	cmd := CommandLine{}
-	return executeQueryStringCommands(c, &cmd, "DELETE")
+	return executeQueryStringCommands(c, "DELETE")
 }
 
-func executeDataCommands(c *cli.Context, cmd Command, verb string) error {
+func executeDataCommands(c *cli.Context, verb string) error {
 	if c.String("data") != "" && c.String("file") != "" {
 		return errors.New("Parameter --data cannot be used with parameter --file")
 	}
@@ -290,16 +630,16 @@ func executeDataCommands(c *cli.Context, cmd Command, verb string) error {
 		return errData
 	}
 
-	config, errConfig := getValidatedConfiguration()
+	config, errConfig := getValidatedConfiguration(c)
 	if errConfig != nil {
 		return errConfig
 	}
 
-	return executeCommands(config, cmd, verb, c.String("resource"), "", json, c.String("selector"), !c.Bool("no-stat"), !c.Bool("no-response"), c.GlobalBool("verbose"))
+	return executeCommands(c, config, verb, c.String("resource"), "", json)
 }
 
-func executeQueryStringCommands(c *cli.Context, cmd Command, verb string) error {
-	config, errConfig := getValidatedConfiguration()
+func executeQueryStringCommands(c *cli.Context, verb string) error {
+	config, errConfig := getValidatedConfiguration(c)
 	if errConfig != nil {
 		return errConfig
 	}
@@ -313,151 +653,60 @@ func executeQueryStringCommands(c *cli.Context, cmd Command, verb string) error
 		queryString = queryStr
 	}
 
-	return executeCommands(config, cmd, verb, c.String("resource"), queryString, "", c.String("selector"), !c.Bool("no-stat"), !c.Bool("no-response"), c.GlobalBool("verbose"))
+	return executeCommands(c, config, verb, c.String("resource"), queryString, "")
 }
 
-func executeCommands(config *Configuration, cmd Command, verb string, resource string, queryString string, jsonData string, selector string, isShowStat bool, isShowResponse bool, isVerbose bool) error {
-	args := []string{
-		"-X",
-		verb,
-	}
+func executeCommands(c *cli.Context, config *Profile, verb string, resource string, queryString string, jsonData string) error {
+	isVerbose := c.GlobalBool("verbose")
+	isShowStat := !c.Bool("no-stat")
+	isShowResponse := !c.Bool("no-response")
+
+	url := fmt.Sprintf("%s%s%s", profileBaseURL(config), resource, queryString)
 
+	var bodyReader io.Reader
 	if jsonData != "" {
-		args = append(args, "-H", "Content-Type: application/json", "-d", jsonData)
+		bodyReader = strings.NewReader(jsonData)
 	}
-
-	url := fmt.Sprintf("%s%s%s", config.URL, resource, queryString)
-	args = append(args, url)
+	req, errReq := http.NewRequest(verb, url, bodyReader)
+	if errReq != nil {
+		return errReq
+	}
+	if jsonData != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if config.Token != "" {
+		req.Header.Set("authorization", fmt.Sprintf("Bearer %s", config.Token))
+	}
+	applyProfileHeaders(config, req)
 
 	fmt.Printf("[%s] %s\n", verb, url)
-	output, err := executeHTTPCommand(cmd, isVerbose, config.Token, args)
+	client, errClient := newAPIClient(isVerbose, config)
+	if errClient != nil {
+		return errClient
+	}
+	body, stats, err := client.do(req)
 	if err != nil {
 		return err
 	}
 	if isShowStat {
-		fmt.Println(output)
+		fmt.Println(stats)
 	}
 
-	jqOutput, errJq := executeJqCommand(cmd, isVerbose, []string{selector})
-	if errJq != nil {
-		return errJq
+	selectorOutput, errSelector := runSelector(c.String("selector"), body)
+	if errSelector != nil {
+		return errSelector
 	}
 	if isShowResponse {
-		fmt.Println(jqOutput)
-	}
-
-	return nil
-}
-
-func executeHTTPCommand(c Command, isVerbose bool, token string, args []string) (string, error) {
-	allArgs := []string{
-		"-o",
-		filepath.Join(os.TempDir(), responseTempFilename),
-	}
-	if token != "" {
-		allArgs = append(allArgs, "-H", fmt.Sprintf("authorization: Bearer %s", token))
-	}
-	for _, a := range args {
-		allArgs = append(allArgs, a)
-	}
-	output, err := execute(c, "httpstat", isVerbose, allArgs)
-	if err != nil {
-		return "", err
-	}
-	return output, nil
-}
-
-func executeJqCommand(c Command, isVerbose bool, args []string) (string, error) {
-	catCommand := c.GetCommand("cat", isVerbose, []string{filepath.Join(os.TempDir(), responseTempFilename)})
-	jqCommand := c.GetCommand("jq", isVerbose, args)
-
-	r, w := io.Pipe()
-	catCommand.Stdout = w
-	jqCommand.Stdin = r
-
-	var jqBuffer bytes.Buffer
-	jqCommand.Stdout = &jqBuffer
-
-	catCommand.Start()
-	jqCommand.Start()
-	catCommand.Wait()
-	w.Close()
-	jqCommand.Wait()
-
-	return string(jqBuffer.Bytes()), nil
-}
-
-func execute(c Command, name string, isVerbose bool, args []string) (string, error) {
-	cmd := c.GetCommand(name, isVerbose, args)
-	byteOutput, err := cmd.Output()
-	return string(byteOutput), err
-}
-
-func writeConfiguration(configPath string, configurationBytes []byte) error {
-	f, errOpen := os.Create(configPath)
-	if errOpen != nil {
-		return errOpen
+		rendered, errRender := renderResponse(c, []byte(selectorOutput))
+		if errRender != nil {
+			return errRender
+		}
+		fmt.Println(rendered)
 	}
-	defer f.Close()
-	f.Write(configurationBytes)
 
 	return nil
 }
 
-func getStoredConfiguration(configPath string, config *Configuration) error {
-	bytes, errRead := ioutil.ReadFile(configPath)
-	if errRead != nil {
-		return errRead
-	}
-	errDeserialise := yaml.Unmarshal([]byte(bytes), config)
-	if errDeserialise != nil {
-		return errDeserialise
-	}
-	return nil
-}
-
-func getConfigurationBytes(url string, token string, config *Configuration) ([]byte, error) {
-	if url != "" {
-		config.URL = url
-	}
-	if token != "" {
-		config.Token = token
-	}
-	bytes, errSerialise := yaml.Marshal(config)
-	if errSerialise != nil {
-		return nil, errSerialise
-	}
-	return bytes, nil
-}
-
-func getConfigPath(configuationFilename string) (string, error) {
-	homeDir, err := homedir.Dir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(homeDir, configuationFilename), nil
-}
-
-func getValidatedConfiguration() (*Configuration, error) {
-	configPath, errHome := getConfigPath(configuationFilename)
-	if errHome != nil {
-		return nil, errHome
-	}
-	config := Configuration{}
-	if err := getStoredConfiguration(configPath, &config); err != nil {
-		return nil, err
-	}
-
-	if config.URL == "" {
-		return nil, errors.New("Please run command 'configure' and try again")
-	}
-
-	if config.Token == "" {
-		return nil, errors.New("Please run command 'login' and try again")
-	}
-	return &config, nil
-}
-
 func isJSON(input string) bool {
 	var js json.RawMessage
 	return json.Unmarshal([]byte(input), &js) == nil
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"github.com/urfave/cli"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	outputRaw   = "raw"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputTable = "table"
+	outputCSV   = "csv"
+)
+
+// renderOutput formats a JSON response body according to the requested
+// --output format, defaulting to the raw body when none is given.
+func renderOutput(format string, raw []byte) (string, error) {
+	switch format {
+	case "", outputRaw:
+		return string(raw), nil
+	case outputJSON:
+		return renderJSON(raw)
+	case outputYAML:
+		return renderYAML(raw)
+	case outputTable:
+		return renderTable(raw)
+	case outputCSV:
+		return renderCSV(raw)
+	default:
+		return "", fmt.Errorf("Unsupported output format '%s'", format)
+	}
+}
+
+func renderYAML(raw []byte) (string, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return "", nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// renderTemplate runs a JSON response body through text/template, giving
+// scripting use cases full control over the rendered shape.
+func renderTemplate(tmplText string, raw []byte) (string, error) {
+	var value interface{}
+	if len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return "", err
+		}
+	}
+
+	tmpl, errParse := template.New("output").Parse(tmplText)
+	if errParse != nil {
+		return "", errParse
+	}
+
+	builder := &strings.Builder{}
+	if err := tmpl.Execute(builder, value); err != nil {
+		return "", err
+	}
+	return builder.String(), nil
+}
+
+// renderResponse picks --template when given, otherwise the --output
+// formatter, so --template takes precedence for scripting use cases.
+func renderResponse(c *cli.Context, raw []byte) (string, error) {
+	if tmplText := c.GlobalString("template"); tmplText != "" {
+		return renderTemplate(tmplText, raw)
+	}
+	return renderOutput(c.GlobalString("output"), raw)
+}
+
+func renderJSON(raw []byte) (string, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return "", nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	pretty, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(pretty), nil
+}
+
+// tableRows decodes raw into a slice of records, wrapping a single object in
+// a one-element slice so both list and get responses render consistently.
+func tableRows(raw []byte) ([]map[string]interface{}, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil, nil
+	}
+
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(raw, &rows); err == nil {
+		return rows, nil
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return nil, fmt.Errorf("response is not an object or array of objects: %v", err)
+	}
+	return []map[string]interface{}{row}, nil
+}
+
+func tableHeaders(rows []map[string]interface{}) []string {
+	seen := map[string]bool{}
+	headers := []string{}
+	for _, row := range rows {
+		for key := range row {
+			if !seen[key] {
+				seen[key] = true
+				headers = append(headers, key)
+			}
+		}
+	}
+	sort.Strings(headers)
+	return headers
+}
+
+func renderTable(raw []byte) (string, error) {
+	rows, err := tableRows(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	headers := tableHeaders(rows)
+
+	builder := &strings.Builder{}
+	writer := tabwriter.NewWriter(builder, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		values := make([]string, len(headers))
+		for i, header := range headers {
+			values[i] = fmt.Sprintf("%v", row[header])
+		}
+		fmt.Fprintln(writer, strings.Join(values, "\t"))
+	}
+	writer.Flush()
+
+	return strings.TrimRight(builder.String(), "\n"), nil
+}
+
+func renderCSV(raw []byte) (string, error) {
+	rows, err := tableRows(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+	headers := tableHeaders(rows)
+
+	builder := &strings.Builder{}
+	writer := csv.NewWriter(builder)
+	if err := writer.Write(headers); err != nil {
+		return "", err
+	}
+	for _, row := range rows {
+		values := make([]string, len(headers))
+		for i, header := range headers {
+			values[i] = fmt.Sprintf("%v", row[header])
+		}
+		if err := writer.Write(values); err != nil {
+			return "", err
+		}
+	}
+	writer.Flush()
+
+	return strings.TrimRight(builder.String(), "\n"), nil
+}